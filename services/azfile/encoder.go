@@ -0,0 +1,153 @@
+package azfile
+
+import (
+	"fmt"
+	"strings"
+)
+
+// illegalChars are characters Azure Files rejects that are legal on POSIX
+// filesystems: quote, backslash, colon, pipe, angle brackets, and the wildcard
+// characters.
+const illegalChars = "\"\\:|<>*?"
+
+// reservedNames are Windows device names Azure Files also rejects, regardless of
+// extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// Encoder translates between filenames go-storage callers use and the filenames
+// Azure Files will accept.
+type Encoder interface {
+	// Encode converts a single path segment into one safe to send to Azure Files,
+	// or returns an error if the mode refuses to do so.
+	Encode(name string) (string, error)
+	// Decode reverses Encode on a segment read back from Azure Files.
+	Decode(name string) string
+}
+
+// NewStrictEncoder returns an Encoder that rejects names containing characters
+// illegal on Azure Files instead of silently altering them.
+func NewStrictEncoder() Encoder {
+	return strictEncoder{}
+}
+
+// NewReplaceEncoder returns an Encoder that maps illegal runes to '_'. Lossy, but
+// keeps plain ASCII trees readable in the Azure portal.
+func NewReplaceEncoder() Encoder {
+	return replaceEncoder{}
+}
+
+// NewRoundtripEncoder returns an Encoder that maps illegal runes into the Unicode
+// private-use area so they decode back to the original rune on read, in the same
+// spirit as rclone's lib/encoder.
+func NewRoundtripEncoder() Encoder {
+	return roundtripEncoder{}
+}
+
+func isIllegalRune(r rune) bool {
+	return r < 0x20 || strings.ContainsRune(illegalChars, r)
+}
+
+func isReservedName(name string) bool {
+	base := name
+	if idx := strings.IndexByte(name, '.'); idx >= 0 {
+		base = name[:idx]
+	}
+	return reservedNames[strings.ToUpper(base)]
+}
+
+type strictEncoder struct{}
+
+func (strictEncoder) Encode(name string) (string, error) {
+	if strings.IndexFunc(name, isIllegalRune) >= 0 {
+		return "", fmt.Errorf("azfile: %q contains characters illegal on Azure Files", name)
+	}
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		return "", fmt.Errorf("azfile: %q has a trailing dot or space, illegal on Azure Files", name)
+	}
+	if isReservedName(name) {
+		return "", fmt.Errorf("azfile: %q is a reserved Windows device name", name)
+	}
+	return name, nil
+}
+
+func (strictEncoder) Decode(name string) string { return name }
+
+type replaceEncoder struct{}
+
+func (replaceEncoder) Encode(name string) (string, error) {
+	var b strings.Builder
+	for _, r := range name {
+		if isIllegalRune(r) {
+			b.WriteRune('_')
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	encoded := strings.TrimRight(b.String(), ". ")
+	if isReservedName(encoded) {
+		encoded = breakReservedName(encoded, "_")
+	}
+	return encoded, nil
+}
+
+func (replaceEncoder) Decode(name string) string { return name }
+
+// roundtripBase is the start of the Unicode private-use area range used to smuggle
+// illegal runes through Azure Files.
+const roundtripBase = 0xF000
+
+// roundtripReservedMarker is a private-use rune spliced into an otherwise-reserved
+// base name (CON, PRN, ...) to break the exact match; Decode drops it unconditionally.
+const roundtripReservedMarker = rune(roundtripBase + 0x100)
+
+type roundtripEncoder struct{}
+
+func (roundtripEncoder) Encode(name string) (string, error) {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		trailing := i == len(runes)-1 && (r == '.' || r == ' ')
+		if isIllegalRune(r) || trailing {
+			b.WriteRune(roundtripBase + r)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+
+	encoded := b.String()
+	if isReservedName(name) {
+		encoded = breakReservedName(encoded, string(roundtripReservedMarker))
+	}
+	return encoded, nil
+}
+
+func (roundtripEncoder) Decode(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r == roundtripReservedMarker:
+			// dropped: inserted only to break a reserved-name match on Encode
+		case r >= roundtripBase && r < roundtripBase+0x100:
+			b.WriteRune(r - roundtripBase)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// breakReservedName splices marker into encoded right before its first literal
+// '.' (the extension boundary Windows' reserved-name check keys off), or appends
+// it when encoded has no extension, so the base name no longer matches exactly.
+func breakReservedName(encoded, marker string) string {
+	if idx := strings.IndexByte(encoded, '.'); idx >= 0 {
+		return encoded[:idx] + marker + encoded[idx:]
+	}
+	return encoded + marker
+}
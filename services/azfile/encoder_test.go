@@ -0,0 +1,83 @@
+package azfile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRoundtripEncoder(t *testing.T) {
+	e := NewRoundtripEncoder()
+
+	cases := []string{
+		"plain-name.txt",
+		"weird:name",
+		"a\"b\\c|d<e>f*g?h",
+		"trailing.",
+		"trailing ",
+	}
+
+	for _, name := range cases {
+		encoded, err := e.Encode(name)
+		if err != nil {
+			t.Fatalf("Encode(%q) returned error: %v", name, err)
+		}
+
+		decoded := e.Decode(encoded)
+		if decoded != name {
+			t.Errorf("Decode(Encode(%q)) = %q, want %q", name, decoded, name)
+		}
+	}
+}
+
+func TestReplaceEncoder(t *testing.T) {
+	e := NewReplaceEncoder()
+
+	encoded, err := e.Encode("weird:name|here")
+	if err != nil {
+		t.Fatalf("Encode returned error: %v", err)
+	}
+	if encoded != "weird_name_here" {
+		t.Errorf("Encode(%q) = %q, want %q", "weird:name|here", encoded, "weird_name_here")
+	}
+}
+
+// TestEncodeProducesLegalNames checks that the default and replace encoders
+// actually neutralize everything Azure Files rejects - reserved device names and
+// a trailing dot/space - not just that Decode(Encode(x)) == x, which trivially
+// holds for an unmodified illegal string.
+func TestEncodeProducesLegalNames(t *testing.T) {
+	cases := []string{"CON", "CON.txt", "PRN", "trailing.", "trailing "}
+
+	for _, e := range []Encoder{NewRoundtripEncoder(), NewReplaceEncoder()} {
+		for _, name := range cases {
+			encoded, err := e.Encode(name)
+			if err != nil {
+				t.Fatalf("Encode(%q) returned error: %v", name, err)
+			}
+			if strings.IndexFunc(encoded, isIllegalRune) >= 0 {
+				t.Errorf("Encode(%q) = %q still contains an illegal rune", name, encoded)
+			}
+			if strings.HasSuffix(encoded, ".") || strings.HasSuffix(encoded, " ") {
+				t.Errorf("Encode(%q) = %q still has a trailing dot or space", name, encoded)
+			}
+			if isReservedName(encoded) {
+				t.Errorf("Encode(%q) = %q is still a reserved Windows device name", name, encoded)
+			}
+		}
+	}
+}
+
+func TestStrictEncoder(t *testing.T) {
+	e := NewStrictEncoder()
+
+	cases := []string{"weird:name", "trailing.", "CON", "CON.txt"}
+	for _, name := range cases {
+		if _, err := e.Encode(name); err == nil {
+			t.Errorf("Encode(%q) expected an error, got none", name)
+		}
+	}
+
+	if _, err := e.Encode("plain-name.txt"); err != nil {
+		t.Errorf("Encode(%q) returned unexpected error: %v", "plain-name.txt", err)
+	}
+}
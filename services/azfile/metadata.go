@@ -0,0 +1,138 @@
+package azfile
+
+import (
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/directory"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/file"
+
+	"go.beyondstorage.io/v5/types"
+)
+
+// ObjectMetadata is azfile's service metadata: the SMB file-attribute bitmask,
+// creation/last-write timestamps, and NTFS security descriptor (by permission
+// key). Attached to a types.Object via SetSystemMetadata/GetSystemMetadata.
+type ObjectMetadata struct {
+	// FileAttributes is the Azure Files attribute bitmask, e.g. "ReadOnly|Archive".
+	FileAttributes string
+	// FileCreationTime is the file's creation time as reported by the service.
+	FileCreationTime time.Time
+	// FileLastWriteTime is the file's last-write time as reported by the service.
+	FileLastWriteTime time.Time
+	// FilePermissionKey identifies the NTFS security descriptor (SDDL) associated
+	// with the file or directory.
+	FilePermissionKey string
+}
+
+// Pair keys for the SMB metadata pairs below.
+const (
+	pairFileAttributes    = "azfile_file_attributes"
+	pairFileCreationTime  = "azfile_file_creation_time"
+	pairFileLastWriteTime = "azfile_file_last_write_time"
+	pairFilePermissionKey = "azfile_file_permission_key"
+)
+
+// WithFileAttributes sets the Azure Files file-attribute bitmask to apply on Create.
+func WithFileAttributes(v string) types.Pair {
+	return types.Pair{Key: pairFileAttributes, Value: v}
+}
+
+// WithFileCreationTime sets the file's creation time to apply on Create.
+func WithFileCreationTime(v time.Time) types.Pair {
+	return types.Pair{Key: pairFileCreationTime, Value: v}
+}
+
+// WithFileLastWriteTime sets the file's last-write time to apply on Create.
+func WithFileLastWriteTime(v time.Time) types.Pair {
+	return types.Pair{Key: pairFileLastWriteTime, Value: v}
+}
+
+// WithFilePermissionKey sets the NTFS security descriptor (by permission key) to
+// apply on Create.
+func WithFilePermissionKey(v string) types.Pair {
+	return types.Pair{Key: pairFilePermissionKey, Value: v}
+}
+
+// smbMetadataFromPairs extracts an ObjectMetadata from any of the pairs above. ok
+// is false when none were present.
+func smbMetadataFromPairs(pairs []types.Pair) (m ObjectMetadata, ok bool) {
+	for _, p := range pairs {
+		switch p.Key {
+		case pairFileAttributes:
+			m.FileAttributes, _ = p.Value.(string)
+			ok = true
+		case pairFileCreationTime:
+			m.FileCreationTime, _ = p.Value.(time.Time)
+			ok = true
+		case pairFileLastWriteTime:
+			m.FileLastWriteTime, _ = p.Value.(time.Time)
+			ok = true
+		case pairFilePermissionKey:
+			m.FilePermissionKey, _ = p.Value.(string)
+			ok = true
+		}
+	}
+	return m, ok
+}
+
+// toSMBProperties converts the attribute/timestamp fields of m into a
+// file.SMBProperties, or nil when none of them were set.
+func (m ObjectMetadata) toSMBProperties() (*file.SMBProperties, error) {
+	if m.FileAttributes == "" && m.FileCreationTime.IsZero() && m.FileLastWriteTime.IsZero() {
+		return nil, nil
+	}
+
+	smb := &file.SMBProperties{}
+	if m.FileAttributes != "" {
+		attrs, err := file.ParseNTFSFileAttributes(&m.FileAttributes)
+		if err != nil {
+			return nil, err
+		}
+		smb.Attributes = attrs
+	}
+	if !m.FileCreationTime.IsZero() {
+		smb.CreationTime = &m.FileCreationTime
+	}
+	if !m.FileLastWriteTime.IsZero() {
+		smb.LastWriteTime = &m.FileLastWriteTime
+	}
+
+	return smb, nil
+}
+
+// toPermissions converts m.FilePermissionKey into a file.Permissions, or nil when
+// it wasn't set.
+func (m ObjectMetadata) toPermissions() *file.Permissions {
+	if m.FilePermissionKey == "" {
+		return nil
+	}
+	return &file.Permissions{PermissionKey: &m.FilePermissionKey}
+}
+
+// toDirectoryCreateOptions converts m into directory.CreateOptions, leaving fields
+// the caller didn't set at the service default.
+func (m ObjectMetadata) toDirectoryCreateOptions() (*directory.CreateOptions, error) {
+	smb, err := m.toSMBProperties()
+	if err != nil {
+		return nil, err
+	}
+
+	return &directory.CreateOptions{
+		FileSMBProperties: smb,
+		FilePermissions:   m.toPermissions(),
+	}, nil
+}
+
+// toFileCreateOptions converts m into file.CreateOptions, leaving fields the
+// caller didn't set at the service default.
+func (m ObjectMetadata) toFileCreateOptions() (*file.CreateOptions, error) {
+	smb, err := m.toSMBProperties()
+	if err != nil {
+		return nil, err
+	}
+
+	return &file.CreateOptions{
+		SMBProperties: smb,
+		Permissions:   m.toPermissions(),
+	}, nil
+}
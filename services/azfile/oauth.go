@@ -0,0 +1,42 @@
+package azfile
+
+import (
+	"go.beyondstorage.io/v5/types"
+)
+
+// OAuthCredential carries an Azure AD (OAuth) credential's tenant and client
+// identifiers plus an optional client secret. The shared go.beyondstorage.io/credential
+// library only recognizes the hmac/apikey/file/env/base64/basic protocols, so OAuth
+// is carried through its own pair instead of a parseable credential string.
+type OAuthCredential struct {
+	// TenantID is the Azure AD tenant to authenticate against.
+	TenantID string
+	// ClientID is the application (client) ID. Required for client-secret and
+	// user-assigned managed identity authentication; leave empty to fall back to
+	// workload-identity / environment-based discovery.
+	ClientID string
+	// ClientSecret authenticates ClientID via a client-secret flow. Leave empty to
+	// use managed identity (when ClientID is set) or the default credential chain.
+	ClientSecret string
+}
+
+// Pair key for WithOAuthCredential.
+const pairOAuthCredential = "azfile_oauth_credential"
+
+// WithOAuthCredential sets an Azure AD (OAuth) credential to authenticate with,
+// in place of the HMAC shared-key credential carried by WithCredential.
+func WithOAuthCredential(v OAuthCredential) types.Pair {
+	return types.Pair{Key: pairOAuthCredential, Value: v}
+}
+
+// oauthCredentialFromPairs extracts the OAuthCredential set via
+// WithOAuthCredential. ok is false when it wasn't present.
+func oauthCredentialFromPairs(pairs []types.Pair) (c OAuthCredential, ok bool) {
+	for _, p := range pairs {
+		if p.Key == pairOAuthCredential {
+			c, ok = p.Value.(OAuthCredential)
+			return
+		}
+	}
+	return
+}
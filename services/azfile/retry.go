@@ -0,0 +1,39 @@
+package azfile
+
+import (
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// RetryPolicy configures how the Track 2 SDK pipeline retries failed requests.
+type RetryPolicy struct {
+	// MaxTries caps the number of attempts, including the first try. Zero means
+	// use the SDK default.
+	MaxTries int32
+	// TryTimeout bounds a single attempt, including the response body read.
+	TryTimeout time.Duration
+	// RetryDelay is the delay before the first retry; it backs off from there.
+	RetryDelay time.Duration
+	// MaxRetryDelay caps the backed-off delay between retries.
+	MaxRetryDelay time.Duration
+}
+
+// toPolicyRetryOptions converts RetryPolicy into policy.RetryOptions, leaving zero
+// fields at the SDK default.
+func (r RetryPolicy) toPolicyRetryOptions() policy.RetryOptions {
+	opts := policy.RetryOptions{
+		TryTimeout:    r.TryTimeout,
+		RetryDelay:    r.RetryDelay,
+		MaxRetryDelay: r.MaxRetryDelay,
+	}
+	// MaxRetries excludes the first try, and the SDK reads zero as its default and
+	// a negative value as "no retries".
+	switch {
+	case r.MaxTries == 1:
+		opts.MaxRetries = -1
+	case r.MaxTries > 1:
+		opts.MaxRetries = r.MaxTries - 1
+	}
+	return opts
+}
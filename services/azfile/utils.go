@@ -2,11 +2,18 @@ package azfile
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/url"
 	"strings"
+	"time"
 
-	"github.com/Azure/azure-storage-file-go/azfile"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/directory"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/fileerror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/service"
 
 	"go.beyondstorage.io/credential"
 	"go.beyondstorage.io/endpoint"
@@ -17,9 +24,11 @@ import (
 
 // Storage is the azfile client.
 type Storage struct {
-	client azfile.DirectoryURL
+	client *directory.Client
 
-	workDir string
+	workDir        string
+	encoder        Encoder
+	requestTimeout time.Duration
 
 	defaultPairs DefaultStoragePairs
 	features     StorageFeatures
@@ -53,11 +62,15 @@ func newStorager(pairs ...types.Pair) (store *Storage, err error) {
 
 	store = &Storage{
 		workDir: "/",
+		encoder: NewRoundtripEncoder(),
 	}
 
 	if opt.HasWorkDir {
 		store.workDir = opt.WorkDir
 	}
+	if opt.HasFilenameEncoding {
+		store.encoder = opt.FilenameEncoding
+	}
 
 	ep, err := endpoint.Parse(opt.Endpoint)
 	if err != nil {
@@ -74,37 +87,93 @@ func newStorager(pairs ...types.Pair) (store *Storage, err error) {
 		return nil, services.PairUnsupportedError{Pair: ps.WithEndpoint(opt.Endpoint)}
 	}
 
-	primaryURL, _ := url.Parse(uri)
-
-	cred, err := credential.Parse(opt.Credential)
-	if err != nil {
-		return nil, err
+	isEmulator := opt.HasEmulator && opt.Emulator
+	if !isEmulator {
+		if parsed, perr := url.Parse(uri); perr == nil && isLoopbackHost(parsed.Hostname()) {
+			isEmulator = true
+		}
 	}
-	if cred.Protocol() != credential.ProtocolHmac {
-		return nil, services.PairUnsupportedError{Pair: ps.WithCredential(opt.Credential)}
+
+	accountName := ""
+	accountKey := ""
+	if isEmulator {
+		accountName, accountKey = azuriteAccountName, azuriteAccountKey
 	}
 
-	credValue, err := azfile.NewSharedKeyCredential(cred.Hmac())
-	if err != nil {
-		return nil, err
+	credString := opt.Credential
+	if credString == "" && isEmulator {
+		credString = fmt.Sprintf("hmac:%s:%s", accountName, accountKey)
+	}
+	if cs, csErr := parseConnectionString(credString); csErr == nil {
+		credString = fmt.Sprintf("hmac:%s:%s", cs.accountName, cs.accountKey)
+		if cs.endpoint != "" {
+			uri = cs.endpoint
+		}
 	}
 
-	p := azfile.NewPipeline(credValue, azfile.PipelineOptions{
-		Retry: azfile.RetryOptions{},
-	})
+	clientOpts := service.ClientOptions{}
+	if opt.HasRetryPolicy {
+		clientOpts.Retry = opt.RetryPolicy.toPolicyRetryOptions()
+	}
+	if opt.HasHTTPClient {
+		clientOpts.Transport = opt.HTTPClient
+	}
 
-	serviceURL := azfile.NewServiceURL(*primaryURL, p)
+	if opt.HasRequestTimeout {
+		store.requestTimeout = opt.RequestTimeout
+	}
 
-	ctx := context.Background()
-	shareURL := serviceURL.NewShareURL(opt.Name)
+	var svcClient *service.Client
+	if oauthCred, ok := oauthCredentialFromPairs(pairs); ok {
+		// OAuth bypasses credString/credential.Parse entirely: the shared credential
+		// library has no protocol for it.
+		tokenCred, credErr := newTokenCredential(oauthCred)
+		if credErr != nil {
+			return nil, credErr
+		}
+		svcClient, err = service.NewClient(uri, tokenCred, &clientOpts)
+	} else {
+		cred, credErr := credential.Parse(credString)
+		if credErr != nil {
+			return nil, credErr
+		}
 
-	workDir := strings.TrimPrefix(store.workDir, "/")
-	store.client = shareURL.NewDirectoryURL(workDir)
-	_, err = store.client.Create(ctx, azfile.Metadata{}, azfile.SMBProperties{})
+		switch cred.Protocol() {
+		case credential.ProtocolHmac:
+			credValue, credErr := service.NewSharedKeyCredential(cred.Hmac())
+			if credErr != nil {
+				return nil, credErr
+			}
+			if isEmulator {
+				uri = strings.TrimSuffix(uri, "/") + "/" + credValue.AccountName()
+			}
+			svcClient, err = service.NewClientWithSharedKeyCredential(uri, credValue, &clientOpts)
+		default:
+			return nil, services.PairUnsupportedError{Pair: ps.WithCredential(opt.Credential)}
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	shareClient := svcClient.NewShareClient(opt.Name)
+
+	ctx, cancel := store.withRequestTimeout(context.Background())
+	defer cancel()
+	workDir := strings.TrimPrefix(store.workDir, "/")
+	store.client = shareClient.NewDirectoryClient(workDir)
+
+	ensureWorkDir := true
+	if opt.HasEnsureWorkDir {
+		ensureWorkDir = opt.EnsureWorkDir
+	}
+	if ensureWorkDir {
+		_, err = store.client.Create(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if opt.HasDefaultStoragePairs {
 		store.defaultPairs = opt.DefaultStoragePairs
 	}
@@ -135,57 +204,80 @@ func formatError(err error) error {
 		return err
 	}
 
-	e, ok := err.(azfile.StorageError)
+	switch {
+	case fileerror.HasCode(err, fileerror.ResourceNotFound, fileerror.ParentNotFound, fileerror.ShareNotFound):
+		return fmt.Errorf("%w: %v", services.ErrObjectNotExist, err)
+	case fileerror.HasCode(err, fileerror.AuthorizationPermissionMismatch, fileerror.InsufficientAccountPermissions, fileerror.AuthenticationFailed):
+		return fmt.Errorf("%w: %v", services.ErrPermissionDenied, err)
+	}
 
-	if ok {
-		switch azfile.StorageErrorCodeType(e.ServiceCode()) {
-		case "":
-			switch e.Response().StatusCode {
-			case fileNotFound:
-				return fmt.Errorf("%w: %v", services.ErrObjectNotExist, err)
-			default:
-				return fmt.Errorf("%w: %v", services.ErrUnexpected, err)
-			}
-		case azfile.StorageErrorCodeResourceNotFound:
-			return fmt.Errorf("%w: %v", services.ErrObjectNotExist, err)
-		case azfile.StorageErrorCodeInsufficientAccountPermissions:
-			return fmt.Errorf("%w: %v", services.ErrPermissionDenied, err)
-		default:
-			return fmt.Errorf("%w: %v", services.ErrUnexpected, err)
-		}
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) && respErr.StatusCode == fileNotFound {
+		return fmt.Errorf("%w: %v", services.ErrObjectNotExist, err)
 	}
 
 	return fmt.Errorf("%w: %v", services.ErrUnexpected, err)
 }
 
-// getAbsPath will calculate object storage's abs path
-func (s *Storage) getAbsPath(path string) string {
+// getAbsPath will calculate object storage's abs path, routed through the
+// configured Encoder since the result is sent to the Azure Files service.
+func (s *Storage) getAbsPath(path string) (string, error) {
 	if strings.HasPrefix(path, s.workDir) {
-		return strings.TrimPrefix(path, "/")
+		return s.encodePath(strings.TrimPrefix(path, "/"))
 	}
 
 	prefix := strings.TrimPrefix(s.workDir, "/")
-	return prefix + path
+	return s.encodePath(prefix + path)
 }
 
-// getRelPath will get object storage's rel path.
+// getRelPath will get object storage's rel path, decoding it back from the
+// on-the-wire Azure Files representation.
 func (s *Storage) getRelPath(path string) string {
 	prefix := strings.TrimPrefix(s.workDir, "/")
-	return strings.TrimPrefix(path, prefix)
+	return s.decodePath(strings.TrimPrefix(path, prefix))
 }
 
-// getRelativePath will get relative path(fileName or directoryName) based on workDir for DirectoryURL or FileURL.
-func (s *Storage) getRelativePath(path string) string {
+// getRelativePath will get relative path(fileName or directoryName) based on
+// workDir for DirectoryURL or FileURL, routed through the configured Encoder since
+// the result is sent to the Azure Files service.
+func (s *Storage) getRelativePath(path string) (string, error) {
 	relativePath := path
 	if strings.HasPrefix(path, s.workDir) {
 		relativePath = strings.TrimPrefix(path, s.workDir)
-		return strings.TrimPrefix(relativePath, "/")
+		relativePath = strings.TrimPrefix(relativePath, "/")
+	}
+
+	return s.encodePath(relativePath)
+}
+
+// encodePath encodes every "/"-separated segment of path through s.encoder.
+func (s *Storage) encodePath(path string) (string, error) {
+	segments := strings.Split(path, "/")
+	for i, v := range segments {
+		enc, err := s.encoder.Encode(v)
+		if err != nil {
+			return "", err
+		}
+		segments[i] = enc
+	}
+
+	return strings.Join(segments, "/"), nil
+}
+
+// decodePath decodes every "/"-separated segment of path through s.encoder.
+func (s *Storage) decodePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, v := range segments {
+		segments[i] = s.encoder.Decode(v)
 	}
 
-	return relativePath
+	return strings.Join(segments, "/")
 }
 
-func (s *Storage) mkDirs(ctx context.Context, path string) (err error) {
+// mkDirs creates path and any missing parent directories. smb, when non-nil, carries
+// the SMB attributes/timestamps/permission key to apply to the leaf directory only;
+// intermediate directories are created with the service defaults.
+func (s *Storage) mkDirs(ctx context.Context, path string, smb *ObjectMetadata) (err error) {
 	if "." == path {
 		return
 	}
@@ -195,7 +287,7 @@ func (s *Storage) mkDirs(ctx context.Context, path string) (err error) {
 	existedDir := ""
 	for i > 0 {
 		existedDir = strings.Join(subDirs[0:i], "/")
-		_, err = s.client.NewDirectoryURL(existedDir).GetProperties(ctx)
+		_, err = s.client.NewSubdirectoryClient(existedDir).GetProperties(ctx, nil)
 		if err == nil {
 			// dir already exists
 			break
@@ -210,14 +302,23 @@ func (s *Storage) mkDirs(ctx context.Context, path string) (err error) {
 	}
 
 	currentDir := existedDir
-	for _, v := range subDirs[i:] {
+	remaining := subDirs[i:]
+	for idx, v := range remaining {
 		if currentDir == "" {
 			currentDir = v
 		} else {
 			currentDir += "/" + v
 		}
 
-		_, err = s.client.NewDirectoryURL(currentDir).Create(ctx, azfile.Metadata{}, azfile.SMBProperties{})
+		var opts *directory.CreateOptions
+		if smb != nil && idx == len(remaining)-1 {
+			opts, err = smb.toDirectoryCreateOptions()
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = s.client.NewSubdirectoryClient(currentDir).Create(ctx, opts)
 		if err != nil {
 			return err
 		}
@@ -226,42 +327,177 @@ func (s *Storage) mkDirs(ctx context.Context, path string) (err error) {
 	return
 }
 
+// withRequestTimeout wraps ctx with s.requestTimeout, when WithRequestTimeout was
+// set, so every SDK call made against this Storage honors it. The returned cancel
+// func is always safe to defer, even when no timeout is configured.
+func (s *Storage) withRequestTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.requestTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.requestTimeout)
+}
+
 func (s *Storage) newObject(done bool) *types.Object {
 	return types.NewObject(s, done)
 }
 
-func (s *Storage) formatFileObject(v azfile.FileItem) (o *types.Object, err error) {
+func (s *Storage) formatFileObject(v *directory.File) (o *types.Object, err error) {
 	o = s.newObject(true)
-	o.ID = v.Name
-	o.Path = s.getRelPath(v.Name)
+	o.ID = s.decodePath(*v.Name)
+	o.Path = s.getRelPath(*v.Name)
 	o.Mode |= types.ModeRead
 
-	if v.Properties.ContentLength != 0 {
-		o.SetContentLength(v.Properties.ContentLength)
+	if v.Properties != nil && v.Properties.ContentLength != nil && *v.Properties.ContentLength != 0 {
+		o.SetContentLength(*v.Properties.ContentLength)
+	}
+
+	var m ObjectMetadata
+	var hasMetadata bool
+	if v.Attributes != nil {
+		m.FileAttributes = *v.Attributes
+		hasMetadata = true
+	}
+	if v.PermissionKey != nil {
+		m.FilePermissionKey = *v.PermissionKey
+		hasMetadata = true
+	}
+	if v.Properties != nil {
+		if v.Properties.CreationTime != nil {
+			m.FileCreationTime = *v.Properties.CreationTime
+			hasMetadata = true
+		}
+		if v.Properties.LastWriteTime != nil {
+			m.FileLastWriteTime = *v.Properties.LastWriteTime
+			hasMetadata = true
+		}
+	}
+	if hasMetadata {
+		o.SetSystemMetadata(m)
 	}
 
 	return
 }
 
-func (s *Storage) formatDirObject(v azfile.DirectoryItem) (o *types.Object, err error) {
+func (s *Storage) formatDirObject(v *directory.Directory) (o *types.Object, err error) {
 	o = s.newObject(true)
-	o.ID = v.Name
-	o.Path = s.getRelPath(v.Name)
+	o.ID = s.decodePath(*v.Name)
+	o.Path = s.getRelPath(*v.Name)
 	o.Mode |= types.ModeDir
 
+	var m ObjectMetadata
+	var hasMetadata bool
+	if v.Attributes != nil {
+		m.FileAttributes = *v.Attributes
+		hasMetadata = true
+	}
+	if v.PermissionKey != nil {
+		m.FilePermissionKey = *v.PermissionKey
+		hasMetadata = true
+	}
+	if v.Properties != nil {
+		if v.Properties.CreationTime != nil {
+			m.FileCreationTime = *v.Properties.CreationTime
+			hasMetadata = true
+		}
+		if v.Properties.LastWriteTime != nil {
+			m.FileLastWriteTime = *v.Properties.LastWriteTime
+			hasMetadata = true
+		}
+	}
+	if hasMetadata {
+		o.SetSystemMetadata(m)
+	}
+
 	return
 }
 
 const (
 	// File not found error.
 	fileNotFound = 404
+
+	// azuriteAccountName and azuriteAccountKey are the well-known credentials of the
+	// Azurite storage emulator, documented at
+	// https://github.com/Azure/Azurite#default-storage-account.
+	azuriteAccountName = "devstoreaccount1"
+	azuriteAccountKey  = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
 )
 
+// newTokenCredential builds an azcore.TokenCredential from an OAuthCredential. It
+// supports client-secret credentials when a client secret is provided, managed
+// identity (including a user-assigned MSI via its client ID) when only a client ID
+// is provided, and falls back to workload-identity / environment-based discovery
+// through azidentity.NewDefaultAzureCredential otherwise.
+func newTokenCredential(cred OAuthCredential) (azcore.TokenCredential, error) {
+	switch {
+	case cred.ClientSecret != "":
+		return azidentity.NewClientSecretCredential(cred.TenantID, cred.ClientID, cred.ClientSecret, nil)
+	case cred.ClientID != "":
+		return azidentity.NewManagedIdentityCredential(&azidentity.ManagedIdentityCredentialOptions{
+			ID: azidentity.ClientID(cred.ClientID),
+		})
+	default:
+		return azidentity.NewDefaultAzureCredential(nil)
+	}
+}
+
+// connectionString holds the fields go-storage cares about out of an Azure
+// Storage connection string.
+type connectionString struct {
+	accountName string
+	accountKey  string
+	endpoint    string
+}
+
+// parseConnectionString parses a `Key=Value;Key=Value` connection string such as
+// `AccountName=...;AccountKey=...;EndpointSuffix=core.chinacloudapi.cn`. It returns
+// an error if s is not a connection string, so callers can fall through to treating
+// s as an ordinary go-storage credential string.
+func parseConnectionString(s string) (cs connectionString, err error) {
+	if !strings.Contains(s, "AccountName=") || !strings.Contains(s, "AccountKey=") {
+		return cs, fmt.Errorf("not a connection string")
+	}
+
+	suffix := "core.windows.net"
+	for _, kv := range strings.Split(s, ";") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "AccountName":
+			cs.accountName = v
+		case "AccountKey":
+			cs.accountKey = v
+		case "EndpointSuffix":
+			suffix = v
+		}
+	}
+
+	if cs.accountName == "" || cs.accountKey == "" {
+		return cs, fmt.Errorf("connection string missing AccountName/AccountKey")
+	}
+
+	cs.endpoint = fmt.Sprintf("https://%s.file.%s", cs.accountName, suffix)
+	return cs, nil
+}
+
+// isLoopbackHost reports whether host is localhost or a loopback IP literal, which
+// indicates the endpoint is an emulator such as Azurite rather than a real Azure
+// Files account.
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 func checkError(err error, expect int) bool {
-	e, ok := err.(azfile.StorageError)
-	if !ok {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) {
 		return false
 	}
 
-	return e.Response().StatusCode == expect
-}
\ No newline at end of file
+	return respErr.StatusCode == expect
+}
@@ -0,0 +1,93 @@
+package azfile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/file"
+)
+
+// failFirstUploader fails the first UploadRange call and succeeds on every call
+// after, while counting how many calls it actually saw.
+type failFirstUploader struct {
+	calls int32
+}
+
+func (u *failFirstUploader) UploadRange(_ context.Context, _ int64, body io.ReadSeekCloser, _ *file.UploadRangeOptions) (file.UploadRangeResponse, error) {
+	atomic.AddInt32(&u.calls, 1)
+	_, _ = io.Copy(io.Discard, body)
+	if atomic.LoadInt32(&u.calls) == 1 {
+		return file.UploadRangeResponse{}, errors.New("fake upload failure")
+	}
+	return file.UploadRangeResponse{}, nil
+}
+
+func TestWriteChunkedStopsDispatchingAfterFirstFailure(t *testing.T) {
+	const chunkSize = 1024
+	const ranges = 8
+
+	u := &failFirstUploader{}
+	s := &Storage{}
+
+	// concurrency=1 forces every range through the same semaphore slot, so the
+	// second range's dispatch always blocks until the first range's UploadRange
+	// has already returned and set firstErr.
+	data := make([]byte, chunkSize*ranges)
+	_, err := s.writeChunked(context.Background(), u, bytes.NewReader(data), int64(len(data)), chunkSize, 1, pairStorageWrite{})
+	if err == nil {
+		t.Fatal("writeChunked expected an error, got none")
+	}
+
+	if got := atomic.LoadInt32(&u.calls); got != 1 {
+		t.Errorf("UploadRange was called %d times in all, want 1 (no range dispatched after the failure)", got)
+	}
+}
+
+func TestClampChunkSize(t *testing.T) {
+	cases := []struct {
+		requested int64
+		want      int64
+	}{
+		{0, defaultWriteChunkSize},
+		{-1, defaultWriteChunkSize},
+		{1024, 1024},
+		{maxWriteChunkSize, maxWriteChunkSize},
+		{maxWriteChunkSize + 1, maxWriteChunkSize},
+	}
+
+	for _, c := range cases {
+		if got := clampChunkSize(c.requested); got != c.want {
+			t.Errorf("clampChunkSize(%d) = %d, want %d", c.requested, got, c.want)
+		}
+	}
+}
+
+func TestChunkCount(t *testing.T) {
+	cases := []struct {
+		size      int64
+		requested int64
+		want      int64
+	}{
+		{0, 0, 0},
+		{1, 0, 1},
+		{defaultWriteChunkSize, 0, 1},
+		{defaultWriteChunkSize + 1, 0, 2},
+		{10 * defaultWriteChunkSize, 0, 10},
+		{10, -1, 1},
+	}
+
+	for _, c := range cases {
+		chunkSize := clampChunkSize(c.requested)
+		var got int64
+		if c.size > 0 {
+			got = (c.size + chunkSize - 1) / chunkSize
+		}
+		if got != c.want {
+			t.Errorf("chunk count for size=%d requested=%d = %d, want %d", c.size, c.requested, got, c.want)
+		}
+	}
+}